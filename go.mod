@@ -0,0 +1,3 @@
+module github.com/mek-apelsin/lmt
+
+go 1.17
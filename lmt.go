@@ -5,40 +5,26 @@
 // about the literate programming sources if you ever experience a crash,
 // or having problem compiling.
 
+//go:build ignore
+
 package main
 
 import (
-	"bufio"
-	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
-)
 
-type File string
-type CodeBlock []CodeLine
-type BlockName string
-type language string
-type CodeLine struct {
-	text   string
-	file   File
-	lang   language
-	number int
-	macro  BlockName
-}
-
-var blocks map[BlockName]CodeBlock
-var files map[File]CodeBlock
-
-type codefence struct {
-	char  string // This should probably be a rune for purity
-	count int
-}
+	"bufio"
+	"encoding/json"
+	"github.com/mek-apelsin/lmt/lsp"
+	"github.com/mek-apelsin/lmt/tangle"
+	"io"
+	"io/fs"
+	"time"
+)
 
 var flags struct {
 	outfile     string
@@ -48,198 +34,29 @@ var flags struct {
 	listblocks  bool
 	listfiles   bool
 	macro       bool
-}
-var namedBlockRe *regexp.Regexp
-var fileBlockRe *regexp.Regexp
-var replaceRe *regexp.Regexp
-
-// Updates the blocks and files map for the markdown read from r.
-func ProcessFile(r io.Reader, inputfilename string) error {
-	scanner := bufio.NewReader(r)
-	var err error
-
-	var line CodeLine
-	line.file = File(inputfilename)
-
-	var inBlock, appending bool
-	var bname BlockName
-	var fname File
-	var block CodeBlock
-	var fence codefence
-	for {
-		line.number++
-		line.text, err = scanner.ReadString('\n')
-		switch err {
-		case io.EOF:
-			return nil
-		case nil:
-			// Nothing special
-		default:
-			return err
-		}
-		if !inBlock {
-			if len(line.text) >= 3 && (line.text[0:3] == "```" || line.text[0:3] == "~~~") {
-				inBlock = true
-				// We were outside of a block and now we are in one,
-				// so just blindly reset the block variable.
-				block = make(CodeBlock, 0)
-				fname, bname, appending, line.lang, fence = parseHeader(line.text)
-				if fname != "" {
-					line.macro = BlockName(fname)
-				}
-				if bname != "" {
-					line.macro = BlockName(fmt.Sprintf(`"%v"`, bname))
-				}
-			}
-			continue
-		}
-		if l := strings.TrimSpace(line.text); len(l) >= fence.count && strings.Replace(l, fence.char, "", -1) == "" {
-			inBlock = false
-			// Update the files map if it's a file.
-			if fname != "" {
-				if appending {
-					files[fname] = append(files[fname], block...)
-				} else {
-					files[fname] = block
-				}
-			}
-
-			// Update the named block map if it's a named block.
-			if bname != "" {
-				if appending {
-					blocks[bname] = append(blocks[bname], block...)
-				} else {
-					blocks[bname] = block
-				}
-			}
-			continue
-		}
-		block = append(block, line)
-	}
-}
-func parseHeader(line string) (File, BlockName, bool, language, codefence) {
-	line = strings.TrimSpace(line) // remove indentation and trailing spaces
-
-	// lets iterate over the regexps we have.
-	for _, re := range []*regexp.Regexp{namedBlockRe, fileBlockRe} {
-		if m := namedMatchesfromRe(re, line); m != nil {
-			var fence codefence
-			fence.char = m["fence"][0:1]
-			fence.count = len(m["fence"])
-			return File(m["file"]), BlockName(m["name"]), (m["append"] == "+="), language(m["language"]), fence
-		}
-	}
-
-	// An empty return value for unnamed or broken fences to codeblocks.
-	return "", "", false, "", codefence{}
-}
-
-// Replace expands all macros in a CodeBlock and returns a CodeBlock with no
-// references to macros.
-func (c CodeBlock) Replace(prefix string) (ret CodeBlock) {
-	var line string
-	for _, v := range c {
-		line = v.text
-		matches := replaceRe.FindStringSubmatch(line)
-		if matches == nil {
-			if v.text != "\n" {
-				v.text = prefix + v.text
-			}
-			ret = append(ret, v)
-			continue
-		}
-		bname := BlockName(matches[2])
-		if val, ok := blocks[bname]; ok {
-			ret = append(ret, val.Replace(prefix+matches[1])...)
-		} else {
-			fmt.Fprintf(os.Stderr, "Warning: Block named %s referenced but not defined.\n", bname)
-			ret = append(ret, v)
-		}
-	}
-	return
-}
-
-// Finalize extract the textual lines from CodeBlocks and (if needed) prepend a
-// notice about "unexpected" filename or line changes, which is extracted from
-// the contained CodeLines. The result is a string with newlines ready to be
-// pasted into a file.
-func (block CodeBlock) Finalize() (ret string) {
-	var prev CodeLine
-	var lineformatstring string
-	var macroformatstring string
-
-	for _, current := range block {
-		if !flags.publishable && (prev.number+1 != current.number || prev.file != current.file) {
-			switch current.lang {
-			case "bash", "shell", "sh", "zsh", "python", "perl":
-				macroformatstring = "# <<< %v >>>\n"
-				lineformatstring = "\n#line %v \"%v\"\n"
-			case "go", "golang":
-				macroformatstring = "//// <<< %v >>>\n"
-				lineformatstring = "\n//line %[2]v:%[1]v\n"
-			case "CPP", "cpp", "Cpp":
-				macroformatstring = "// <<< %v >>>\n"
-				lineformatstring = "\n#line %v \"%v\"\n"
-			case "C", "c":
-				// No surefire way to make line comments in c, we might be in a comment block already.
-				lineformatstring = "\n#line %v \"%v\"\n"
-			}
-			if flags.macro && macroformatstring != "" && prev.macro != current.macro {
-				ret += fmt.Sprintf(macroformatstring, current.macro)
-			}
-			if lineformatstring != "" {
-				ret += fmt.Sprintf(lineformatstring, current.number, current.file)
-			}
-		}
-		ret += current.text
-		prev = current
-	}
-	return
-}
-
-// namedMatchesfromRe takes an regexp and a string to match and returns a map
-// of named groups to the matches. If not matches are found it returns nil.
-func namedMatchesfromRe(re *regexp.Regexp, toMatch string) (ret map[string]string) {
-	substrings := re.FindStringSubmatch(toMatch)
-	if substrings == nil {
-		return nil
-	}
-
-	ret = make(map[string]string)
-	names := re.SubexpNames()
-
-	for i, s := range substrings {
-		ret[names[i]] = s
-	}
-	// The names[0] and names[x] from unnamed regex grous are an empty string.
-	// Instead of checking every names[x] we simply overwrite the previous
-	// ret[""] and discard it at the end.
-	delete(ret, "")
-	return
-}
-
-// getBlockByName takes a string as a name and use it as a key in files and
-// blocks and return the first codeblock it could find. If no codeblocks are
-// found by that name getBlockByName returns an error.
-func getBlockByName(bn string) (CodeBlock, error) {
-	// TODO: Why not make files a simple list and store all codeblocks in blocks?
-	if _, filesiscb := files[File(bn)]; filesiscb {
-		return files[File(bn)], nil
-	}
-	if _, blockiscb := blocks[BlockName(bn)]; blockiscb {
-		return blocks[BlockName(bn)], nil
-	}
-	return nil, errors.New("No CodeBlock by that name")
+	weave       bool
+	untangle    string
+	watch       bool
+	check       bool
+	dryrun      bool
+	config      string
+	sourcemap   bool
+	tags        string
+	include     patterns
+	exclude     patterns
+	inputs      bool
+	stdinName   string
+	txtar       bool
+	werror      bool
+	lint        bool
+	graph       string
+	json        bool
+	lsp         bool
+	indented    bool
 }
 
 func main() {
 
-	// Initialize the maps
-	blocks = make(map[BlockName]CodeBlock)
-	files = make(map[File]CodeBlock)
-	namedBlockRe = regexp.MustCompile("^(?P<fence>`{3,}|~{3,})\\s?(?P<language>\\w*)\\s*\"(?P<name>.+)\"\\s*(?P<append>[+][=])?$")
-	fileBlockRe = regexp.MustCompile("^(?P<fence>`{3,}|~{3,})\\s?(?P<language>\\w+)\\s+(?P<file>[\\w\\.\\-\\/]+)\\s*(?P<append>[+][=])?$")
-	replaceRe = regexp.MustCompile(`^(?P<prefix>\s*)(?:<<|//)<(?P<name>.+)>>>\s*$`)
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] files...\n", os.Args[0])
 		flag.PrintDefaults()
@@ -251,78 +68,677 @@ func main() {
 	flag.BoolVar(&flags.listblocks, "l", false, "List all codeblocks.")
 	flag.BoolVar(&flags.listfiles, "f", false, "List all output files.")
 	flag.BoolVar(&flags.macro, "m", false, "macro names added in comments")
-	flag.Parse()
+	flag.BoolVar(&flags.weave, "weave", false, "weave the markdown into a HTML document and print to standard out.")
+	flag.StringVar(&flags.untangle, "untangle", "", "rewrite the markdown with the changes made by hand in a generated file.")
+	flag.BoolVar(&flags.watch, "watch", false, "keep running and tangle the files again whenever they change.")
+	flag.BoolVar(&flags.check, "check", false, "check that the files on disk are up to date, print a diff for those which are not.")
+	flag.BoolVar(&flags.dryrun, "n", false, "dry run, list the files which would be written and how they would change.")
+	flag.StringVar(&flags.config, "config", "", "read the configuration from this file instead of lmt.json.")
+	flag.BoolVar(&flags.sourcemap, "sourcemap", false, "write a source map next to every generated file.")
+	flag.StringVar(&flags.tags, "tags", "", "comma separated list of tags selecting the tagged code blocks to read.")
+	flag.Var(&flags.include, "include", "only read files matching this glob pattern from directories and globs, can be repeated.")
+	flag.Var(&flags.exclude, "exclude", "skip files matching this glob pattern in directories and globs, can be repeated.")
+	flag.BoolVar(&flags.inputs, "inputs", false, "print the input files in the order they are read.")
+	flag.StringVar(&flags.stdinName, "stdin-name", "stdin", "name of the markdown read from standard input, given as -, in line directives and errors.")
+	flag.BoolVar(&flags.txtar, "txtar", false, "write all files to standard out as a txtar archive, instead of to disk.")
+	flag.BoolVar(&flags.werror, "Werror", false, "treat warnings as errors.")
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		flags.lint = true
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	flag.StringVar(&flags.graph, "graph", "", "print the graph of files and blocks in this format, dot or mermaid.")
+	flag.BoolVar(&flags.json, "json", false, "print the files and blocks read, as JSON.")
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		flags.lsp = true
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	flag.BoolVar(&flags.indented, "indented", false, "read indented code blocks named by an annotation, <!-- lmt: main.go -->.")
+	parseFlags()
 
-	for _, file := range flag.Args() {
+	var status int
+	inputs, errs := resolveInputs(flag.Args())
+	t := newTangler()
+	for _, err := range errs {
+		reportError(t, err, "", 0)
+	}
+	for _, file := range inputs {
+		if file == "-" {
+			if err := t.ProcessFile(os.Stdin, flags.stdinName); err != nil {
+				reportError(t, err, tangle.File(flags.stdinName), 0)
+			}
+			continue
+		}
 		f, err := os.Open(file)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, "error: ", err)
+			reportError(t, err, "", 0)
 			continue
 		}
 
-		if err := ProcessFile(f, file); err != nil {
-			fmt.Fprintln(os.Stderr, "error: ", err)
+		if err := t.ProcessFile(f, file); err != nil {
+			reportError(t, err, tangle.File(file), 0)
 		}
 		// Don't defer since we're in a loop, we don't want to wait until the function
 		// exits.
 		f.Close()
 	}
 	if flags.outfile != "" {
-		f := make(map[File]CodeBlock)
-		if files[File(flags.outfile)] != nil {
-			f[File(flags.outfile)] = files[File(flags.outfile)]
+		f := make(map[tangle.File]tangle.CodeBlock)
+		if t.Files[tangle.File(flags.outfile)] != nil {
+			f[tangle.File(flags.outfile)] = t.Files[tangle.File(flags.outfile)]
 		} else {
-			fmt.Fprintf(os.Stderr, "Warning: File named \"%s\" requested but not defined.\n", flags.outfile)
+			report(tangle.Diagnostic{Severity: tangle.Error, Message: fmt.Sprintf("file \"%s\" requested but not defined", flags.outfile)})
 		}
-		files = f
+		t.Files = f
 	}
 	switch {
 	case flags.listfiles:
-		fn := make([]string, 0, len(files))
-		for n := range files {
+		fn := make([]string, 0, len(t.Files))
+		for n := range t.Files {
 			fn = append(fn, string(n))
 		}
 		sort.Strings(fn)
 		fmt.Println(strings.Join(fn, "\n"))
 	case flags.listblocks:
-		bn := make([]string, 0, len(blocks))
-		for n := range blocks {
-			bn = append(bn, string(n))
+		bn := make([]string, 0, len(t.Blocks))
+		for n := range t.Blocks {
+			name := string(n)
+			if tags := t.BlockTags(n); len(tags) > 0 {
+				name += " [tags=" + strings.Join(tags, ",") + "]"
+			}
+			bn = append(bn, name)
 		}
 		sort.Strings(bn)
 		fmt.Println(strings.Join(bn, "\n"))
 	case flags.concatenate != "", flags.extract != "":
 		for i, v := range map[rune]string{'c': flags.concatenate, 'e': flags.extract} {
 			if v != "" {
-				cb, err := getBlockByName(v)
+				cb, err := t.GetBlockByName(v)
 				if err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: Block named \"%s\" requested but not defined.\n", v)
-					return
+					report(tangle.Diagnostic{Severity: tangle.Error, Message: fmt.Sprintf("block \"%s\" requested but not defined", v)})
+					continue
 				}
 				switch i {
 				case 'c':
-					fmt.Fprintf(os.Stdout, "%s", cb.Finalize())
+					fmt.Fprintf(os.Stdout, "%s", t.Finalize(cb))
 				case 'e':
-					fmt.Fprintf(os.Stdout, "%s", cb.Replace("").Finalize())
+					expanded, err := t.Replace(cb, "")
+					if err != nil {
+						reportError(t, err, "", 0)
+						continue
+					}
+					fmt.Fprintf(os.Stdout, "%s", t.Finalize(expanded))
 				}
 			}
 		}
+	case flags.weave:
+		if err := t.Weave(os.Stdout); err != nil {
+			reportError(t, err, "", 0)
+		}
+	case flags.untangle != "":
+		f, err := os.Open(flags.untangle)
+		if err != nil {
+			reportError(t, err, "", 0)
+			break
+		}
+		edits, errs := t.Untangle(tangle.File(flags.untangle), f)
+		f.Close()
+		for _, err := range errs {
+			reportError(t, err, tangle.File(flags.untangle), 0)
+		}
+		applyEdits(t, edits)
+	case flags.watch:
+		watch(flag.Args())
+	case flags.check:
+		for _, filename := range sortedFiles(t) {
+			expanded, ok := expand(t, filename)
+			if !ok {
+				continue
+			}
+			files := outputs(t, filename, expanded)
+			for _, name := range sortedOutputs(files) {
+				old, err := os.ReadFile(string(name))
+				oldname := "a/" + string(name)
+				if os.IsNotExist(err) {
+					oldname = "/dev/null"
+				} else if err != nil {
+					reportError(t, err, "", 0)
+					continue
+				}
+				if d := tangle.UnifiedDiff(oldname, string(old), "b/"+string(name), files[name]); d != "" {
+					fmt.Print(d)
+					status = 1
+				}
+			}
+		}
+	case flags.dryrun:
+		dirs := make(map[string]bool)
+		for _, filename := range sortedFiles(t) {
+			expanded, ok := expand(t, filename)
+			if !ok {
+				continue
+			}
+			if dir := filepath.Dir(string(filename)); dir != "." && !dirs[dir] {
+				dirs[dir] = true
+				if _, err := os.Stat(dir); os.IsNotExist(err) {
+					file, line := t.Origin(filename)
+					report(tangle.Diagnostic{File: file, Line: line, Severity: tangle.Warning, Message: fmt.Sprintf("directory \"%s\" would be created", dir)})
+				}
+			}
+			files := outputs(t, filename, expanded)
+			for _, name := range sortedOutputs(files) {
+				old, err := os.ReadFile(string(name))
+				switch {
+				case os.IsNotExist(err):
+					fmt.Printf("new        %v\n", name)
+				case err != nil:
+					reportError(t, err, "", 0)
+				case string(old) == files[name]:
+					fmt.Printf("unchanged  %v\n", name)
+				default:
+					added, removed := tangle.DiffStat(string(old), files[name])
+					fmt.Printf("modified   %v (+%d -%d)\n", name, added, removed)
+				}
+			}
+		}
+	case flags.inputs:
+		for _, file := range inputs {
+			fmt.Println(file)
+		}
+	case flags.txtar:
+		archive := make(map[tangle.File]string)
+		for filename := range t.Files {
+			expanded, ok := expand(t, filename)
+			if !ok {
+				continue
+			}
+			for name, content := range outputs(t, filename, expanded) {
+				archive[name] = content
+			}
+		}
+		if err := writeTxtar(os.Stdout, archive); err != nil {
+			reportError(t, err, "", 0)
+		}
+	case flags.lint:
+		for _, d := range t.Lint() {
+			report(d)
+			status = 1
+		}
+	case flags.graph != "":
+		if err := t.Graph(os.Stdout, flags.graph); err != nil {
+			reportError(t, err, "", 0)
+		}
+	case flags.json:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(t.Model()); err != nil {
+			reportError(t, err, "", 0)
+		}
+	case flags.lsp:
+		load := func(open map[string]string) *tangle.Tangler { return lspTangler(flag.Args(), open) }
+		if err := lsp.Serve(os.Stdin, os.Stdout, load); err != nil {
+			reportError(t, err, "", 0)
+		}
 	default:
-		for filename, codeblock := range files {
-			if dir := filepath.Dir(string(filename)); dir != "." {
-				if err := os.MkdirAll(dir, 0775); err != nil {
-					fmt.Fprintf(os.Stderr, "%v\n", err)
+		for filename := range t.Files {
+			expanded, ok := expand(t, filename)
+			if !ok {
+				continue
+			}
+			for name, content := range outputs(t, filename, expanded) {
+				if _, err := writeFile(name, content); err != nil {
+					file, line := t.Origin(filename)
+					reportError(t, err, file, line)
 				}
 			}
+		}
+	}
+	if diagnostics[tangle.Error] > 0 {
+		status = 1
+	}
+	os.Exit(status)
+}
 
-			f, err := os.Create(string(filename))
+// applyEdits rewrites the lines of the markdown changed by edits. It reports
+// the edits it can not make, after trying all of them.
+func applyEdits(t *tangle.Tangler, edits []tangle.Edit) {
+	byFile := make(map[tangle.File][]tangle.Edit)
+	var order []tangle.File
+	for _, e := range edits {
+		if byFile[e.File] == nil {
+			order = append(order, e.File)
+		}
+		byFile[e.File] = append(byFile[e.File], e)
+	}
+	for _, file := range order {
+		content, err := os.ReadFile(string(file))
+		if err != nil {
+			reportError(t, err, file, 0)
+			continue
+		}
+		lines := strings.SplitAfter(string(content), "\n")
+		applied := 0
+		for _, e := range byFile[file] {
+			if e.Line > len(lines) || lines[e.Line-1] != e.Old {
+				report(tangle.Diagnostic{File: file, Line: e.Line, Severity: tangle.Error, Message: "the markdown has changed since it was tangled"})
+				continue
+			}
+			lines[e.Line-1] = e.New
+			applied++
+			fmt.Printf("%v:%v: updated\n", file, e.Line)
+		}
+		if applied == 0 {
+			continue
+		}
+		if _, err := writeFile(file, strings.Join(lines, "")); err != nil {
+			reportError(t, err, file, 0)
+		}
+	}
+}
+
+// watch tangles the inputs named by args every time one of them, or a file
+// included by them, changes. It never returns.
+func watch(args []string) {
+	seen := make(map[string]string)
+	written := make(map[tangle.File]string)
+	var included []string
+	var previous string
+	for ; ; time.Sleep(500 * time.Millisecond) {
+		inputs, errs := resolveInputs(args)
+		changed := strings.Join(inputs, "\n") != previous
+		previous = strings.Join(inputs, "\n")
+		for _, file := range append(inputs, included...) {
+			var state string
+			if info, err := os.Stat(file); err == nil {
+				state = fmt.Sprintf("%v %v", info.Size(), info.ModTime())
+			}
+			if seen[file] != state {
+				seen[file] = state
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		t := newTangler()
+		for _, err := range errs {
+			reportError(t, err, "", 0)
+		}
+		for _, file := range inputs {
+			if file == "-" {
+				if err := t.ProcessFile(os.Stdin, flags.stdinName); err != nil {
+					reportError(t, err, tangle.File(flags.stdinName), 0)
+				}
+				continue
+			}
+			f, err := os.Open(file)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "%v\n", err)
+				reportError(t, err, "", 0)
 				continue
 			}
-			fmt.Fprintf(f, "%s", codeblock.Replace("").Finalize())
-			// We don't defer this so that it'll get closed before the loop finishes.
+
+			if err := t.ProcessFile(f, file); err != nil {
+				reportError(t, err, tangle.File(file), 0)
+			}
+			// Don't defer since we're in a loop, we don't want to wait until the function
+			// exits.
+			f.Close()
+		}
+		included = t.Included()
+		if flags.outfile != "" {
+			f := make(map[tangle.File]tangle.CodeBlock)
+			if t.Files[tangle.File(flags.outfile)] != nil {
+				f[tangle.File(flags.outfile)] = t.Files[tangle.File(flags.outfile)]
+			} else {
+				report(tangle.Diagnostic{Severity: tangle.Error, Message: fmt.Sprintf("file \"%s\" requested but not defined", flags.outfile)})
+			}
+			t.Files = f
+		}
+		for filename := range t.Files {
+			expanded, ok := expand(t, filename)
+			if !ok {
+				continue
+			}
+			for name, content := range outputs(t, filename, expanded) {
+				if written[name] == content {
+					continue
+				}
+				ok, err := writeFile(name, content)
+				if err != nil {
+					file, line := t.Origin(filename)
+					reportError(t, err, file, line)
+					continue
+				}
+				written[name] = content
+				if ok {
+					fmt.Printf("%v: written\n", name)
+				}
+			}
+		}
+	}
+}
+
+// newTangler returns a new Tangler with the options and configuration given
+// as flags.
+func newTangler() *tangle.Tangler {
+	var tags []string
+	for _, tag := range strings.Split(flags.tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	t := tangle.New(tangle.Options{Publishable: flags.publishable, Macro: flags.macro, Tags: tags, Indented: flags.indented})
+	t.Report = report
+	cfg, err := readConfig()
+	if err != nil {
+		reportError(t, err, "", 0)
+	}
+	for _, l := range cfg.Languages {
+		t.AddLanguage(l)
+	}
+	return t
+}
+
+// readConfig reads the configuration file, lmt.json is optional but a file
+// given with -config is not.
+func readConfig() (tangle.Config, error) {
+	name := flags.config
+	if name == "" {
+		name = "lmt.json"
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return tangle.Config{}, nil
+		}
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return tangle.Config{}, err
+	}
+	defer f.Close()
+	cfg, err := tangle.ReadConfig(f)
+	if err != nil {
+		return cfg, fmt.Errorf("%v: %v", name, err)
+	}
+	return cfg, nil
+}
+
+// writeFile writes content to filename, unless the file already has that
+// content. The file is written to a temporary file which is renamed to
+// filename, so a crash never leaves a half written file behind. It reports if
+// the file was written.
+func writeFile(filename tangle.File, content string) (bool, error) {
+	name := string(filename)
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(name); err == nil {
+		mode = info.Mode().Perm()
+		if old, err := os.ReadFile(name); err == nil && string(old) == content {
+			return false, nil
+		}
+	}
+
+	dir := filepath.Dir(name)
+	if dir != "." {
+		if err := os.MkdirAll(dir, 0775); err != nil {
+			return false, err
+		}
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(name)+".*")
+	if err != nil {
+		return false, err
+	}
+	_, err = tmp.WriteString(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return false, err
+	}
+	return true, nil
+}
+
+// sortedFiles returns the names of the files of t in sorted order.
+func sortedFiles(t *tangle.Tangler) []tangle.File {
+	fn := make([]tangle.File, 0, len(t.Files))
+	for n := range t.Files {
+		fn = append(fn, n)
+	}
+	sort.Slice(fn, func(i, j int) bool { return fn[i] < fn[j] })
+	return fn
+}
+
+// outputs returns the files to write for filename, generated from the
+// expanded block: the file itself, and with -sourcemap its source map.
+func outputs(t *tangle.Tangler, filename tangle.File, expanded tangle.CodeBlock) map[tangle.File]string {
+	content := t.Finalize(expanded)
+	if !flags.sourcemap {
+		return map[tangle.File]string{filename: content}
+	}
+	mapname, sourcemap := t.SourceMap(filename, expanded)
+	return map[tangle.File]string{
+		filename: t.LinkSourceMap(filename, content),
+		mapname:  sourcemap,
+	}
+}
+
+// patterns is a list of glob patterns given as a repeatable flag.
+type patterns []string
+
+func (p *patterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patterns) Set(pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return err
+	}
+	*p = append(*p, pattern)
+	return nil
+}
+
+// match reports if path, or its base name, matches one of the patterns.
+func (p patterns) match(path string) bool {
+	for _, pattern := range p {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// inputPatterns are the files read from directories and globs, unless
+// -include is given.
+var inputPatterns = patterns{
+	"*.md", "*.markdown",
+	"*.org",
+	"*.adoc", "*.asciidoc", "*.rst",
+}
+
+// selectedInput reports if a file found in a directory or by a glob is read.
+func selectedInput(path string) bool {
+	include := flags.include
+	if len(include) == 0 {
+		include = inputPatterns
+	}
+	return include.match(path) && !flags.exclude.match(path)
+}
+
+// resolveInputs returns the files named by args, in the order they are read.
+// Standard input is named -.
+func resolveInputs(args []string) (files []string, errs []error) {
+	seen := make(map[string]bool)
+	add := func(file string) {
+		if clean := filepath.Clean(file); !seen[clean] {
+			seen[clean] = true
+			files = append(files, file)
+		}
+	}
+	for _, arg := range args {
+		if arg == "-" {
+			add(arg)
+			continue
+		}
+		info, err := os.Stat(arg)
+		switch {
+		case err == nil && info.IsDir():
+			err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+				switch {
+				case err != nil:
+					return err
+				case d.IsDir() && path != arg && (strings.HasPrefix(d.Name(), ".") || flags.exclude.match(path)):
+					return filepath.SkipDir
+				case !d.IsDir() && selectedInput(path):
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case err == nil:
+			add(arg)
+		default:
+			matches, _ := filepath.Glob(arg)
+			if len(matches) == 0 {
+				errs = append(errs, err)
+			}
+			for _, m := range matches {
+				if info, err := os.Stat(m); err == nil && !info.IsDir() && selectedInput(m) {
+					add(m)
+				}
+			}
+		}
+	}
+	return files, errs
+}
+
+// parseFlags parses the flags of the command line, also those after the
+// inputs, and leaves the inputs in flag.Args().
+func parseFlags() {
+	args := os.Args[1:]
+	var inputs []string
+	for {
+		flag.CommandLine.Parse(args)
+		rest := flag.Args()
+		if n := len(args) - len(rest); len(rest) == 0 || n > 0 && args[n-1] == "--" {
+			inputs = append(inputs, rest...)
+			break
+		}
+		inputs = append(inputs, rest[0])
+		args = rest[1:]
+	}
+	flag.CommandLine.Parse(append([]string{"--"}, inputs...))
+}
+
+// writeTxtar writes files to w as a txtar archive, in sorted order.
+func writeTxtar(w io.Writer, files map[tangle.File]string) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	out := bufio.NewWriter(w)
+	for _, name := range names {
+		content := files[tangle.File(name)]
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		fmt.Fprintf(out, "-- %s --\n%s", name, content)
+	}
+	return out.Flush()
+}
+
+// diagnostics counts the diagnostics reported, by severity.
+var diagnostics = make(map[tangle.Severity]int)
+
+// report prints d to standard error. Warnings are errors with -Werror.
+func report(d tangle.Diagnostic) {
+	if flags.werror {
+		d.Severity = tangle.Error
+	}
+	diagnostics[d.Severity]++
+	fmt.Fprintln(os.Stderr, d)
+}
+
+// reportError reports err as an error, where t knows it happened or at file
+// and line.
+func reportError(t *tangle.Tangler, err error, file tangle.File, line int) {
+	d, ok := t.Diagnose(err)
+	if !ok {
+		d.File, d.Line = file, line
+	}
+	report(d)
+}
+
+// expand expands the file filename of t, and reports the error if it can
+// not be expanded.
+func expand(t *tangle.Tangler, filename tangle.File) (tangle.CodeBlock, bool) {
+	expanded, err := t.Replace(t.Files[filename], "")
+	if err != nil {
+		file, line := t.Origin(filename)
+		reportError(t, fmt.Errorf("%v: %w", filename, err), file, line)
+		return nil, false
+	}
+	return expanded, true
+}
+
+// sortedOutputs returns the names of the files in files, in sorted order.
+func sortedOutputs(files map[tangle.File]string) []tangle.File {
+	names := make([]tangle.File, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// lspTangler returns a Tangler with the inputs named by args. The documents
+// in open, by absolute path, are read instead of the files on disk.
+func lspTangler(args []string, open map[string]string) *tangle.Tangler {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	inputs, _ := resolveInputs(args)
+	t := newTangler()
+	t.Publishable, t.Macro = true, false
+	t.Report = func(tangle.Diagnostic) {}
+	read := make(map[string]bool)
+	for _, file := range inputs {
+		path, err := filepath.Abs(file)
+		if err != nil || read[path] {
+			continue
+		}
+		read[path] = true
+		if text, ok := open[path]; ok {
+			t.ProcessFile(strings.NewReader(text), path)
+			continue
+		}
+		if f, err := os.Open(path); err == nil {
+			t.ProcessFile(f, path)
 			f.Close()
 		}
 	}
+	rest := make([]string, 0, len(open))
+	for path := range open {
+		if !read[path] {
+			rest = append(rest, path)
+		}
+	}
+	sort.Strings(rest)
+	for _, path := range rest {
+		t.ProcessFile(strings.NewReader(open[path]), path)
+	}
+	return t
 }
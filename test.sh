@@ -6,7 +6,7 @@ has() {
 		command -v "$c" &>/dev/null || errexit "Missing command $c"
 	done
 }
-test "$1" == "--help" || test "$1" == -h && { echo "subcommands: reseed and nofail. reseed takes parameter 'all' or number denoting source to reseed. Nofails continues without stopping at failing tests."; exit; }
+test "$1" == "--help" || test "$1" == -h && { echo "subcommands: reseed and nofail. reseed takes parameter 'all' or number denoting source to reseed, 'weave' to reseed the woven fixtures or 'fixtures' to reseed the other fixtures. Nofails continues without stopping at failing tests."; exit; }
 
 has go lmt
 
@@ -20,12 +20,16 @@ cd "$DIR"
 fn=0
 for f in ../../README.md ../../addons/*; do
 	fa+=("$f")
-	lmt -p -e main.go "${fa[@]}" > ./base.go
+	# lmt might be more than one file (and package), tangle all of them and
+	# build the binary once for every source.
+	rm -rf ./base && mkdir ./base
+	(cd ./base && lmt -p "${fa[@]/#/../}")
+	go build -C ./base -o ../base.bin ./main.go
 	unset "fi"
 	in=0
 	for i in ../../README.md ../../addons/* ;do
 		fi+=("$i")
-		test -f ../../tests/output/"$fn.$in" && go run ./base.go "${fi[@]}" || continue
+		test -f ../../tests/output/"$fn.$in" && ./base.bin "${fi[@]}" || { in=$((in+1)); continue ;}
 		test "$1" == reseed && { test "$2" == "$in" || test $2 == "all" ;} && cp main.go ../../tests/output/"$fn.$in" ||
 			diff --ignore-matching-lines='^//line' -u ../../tests/output/"$fn.$in" main.go || test "$1" == "nofail" ||
 			{ cp main.go ../../err.out.go  ; errexit "Build failed with lmt from \"$f\" and input \"$i\". Output saved in err.out.go" ;}
@@ -34,3 +38,27 @@ for f in ../../README.md ../../addons/*; do
 	fn=$((fn+1))
 done
 
+# Weave the fixtures in tests/weave with the last lmt built, and compare them
+# with the HTML they should give.
+bin=$(pwd)/base.bin
+for md in ../../tests/weave/*.md; do
+	test -f "$md" || continue
+	html="${md%.md}.html"
+	test "$1" == reseed && test "$2" == weave && { (cd ../../tests/weave && "$bin" -weave "$(basename "$md")") > "$html" ; continue ;}
+	(cd ../../tests/weave && "$bin" -weave "$(basename "$md")") | diff -u "$html" - || test "$1" == "nofail" ||
+		errexit "Weaving \"$md\" does not give \"$html\""
+done
+
+# Run the fixtures in tests/fixtures with the last lmt built. A fixture is a
+# directory with the files it reads and cmd, a script run in a copy of it with
+# lmt in the path. What it prints, to standard output and error, is compared
+# with want.
+mkdir -p ./bin && ln -sf "$bin" ./bin/lmt
+for fx in ../../tests/fixtures/*/; do
+	test -f "$fx/cmd" || continue
+	rm -rf ./fixture && cp -r "$fx" ./fixture && rm -f ./fixture/want
+	got=$(cd ./fixture && PATH="$(pwd)/../bin:$PATH" sh ./cmd 2>&1 || true)
+	test "$1" == reseed && test "$2" == fixtures && { printf '%s\n' "$got" > "$fx/want" ; continue ;}
+	printf '%s\n' "$got" | diff -u "$fx/want" - || test "$1" == "nofail" ||
+		errexit "The fixture \"$fx\" does not give \"${fx}want\""
+done